@@ -2,11 +2,65 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
 	"dagger/xs/internal/dagger"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 type Xs struct{}
 
+// buildFunc is the signature shared by the per-target build methods that
+// BuildAll dispatches to (MacosBuild, LinuxArm64Build, LinuxAmd64Build, ...),
+// so it can call them generically. AndroidBuild takes extra parameters and
+// is not one of them.
+type buildFunc func(ctx context.Context, src *dagger.Directory, version string) *dagger.File
+
+// buildTarget pairs a build method with the Rust target triple it produces,
+// so BuildAll can record the triple (not just the short dispatch name) in
+// manifest.json.
+type buildTarget struct {
+	build  buildFunc
+	triple string
+}
+
+// artifactMeta is a single entry in manifest.json, describing one
+// built archive (a .tar.gz, or a .zip for the Windows targets).
+type artifactMeta struct {
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	Filename   string `json:"filename"`
+	SHA256     string `json:"sha256"`
+	SHA512     string `json:"sha512"`
+	Size       int    `json:"size_bytes"`
+	Version    string `json:"version"`
+	StartedAt  string `json:"build_started_at"`
+	FinishedAt string `json:"build_finished_at"`
+	DurationMs int64  `json:"build_duration_ms"`
+}
+
+// buildTargets maps the target names accepted by BuildAll to the build
+// method that produces them and the Rust target triple each one builds for.
+func (m *Xs) buildTargets() map[string]buildTarget {
+	return map[string]buildTarget{
+		"macos":           {m.MacosBuild, "aarch64-apple-darwin"},
+		"macos-amd64":     {m.MacosAmd64Build, "x86_64-apple-darwin"},
+		"linux-arm64":     {m.LinuxArm64Build, "aarch64-unknown-linux-musl"},
+		"linux-amd64":     {m.LinuxAmd64Build, "x86_64-unknown-linux-musl"},
+		"linux-amd64-gnu": {m.LinuxAmd64GnuBuild, "x86_64-unknown-linux-gnu"},
+		"linux-arm64-gnu": {m.LinuxArm64GnuBuild, "aarch64-unknown-linux-gnu"},
+		"windows-amd64":   {m.WindowsAmd64Build, "x86_64-pc-windows-gnu"},
+		"windows-arm64":   {m.WindowsArm64Build, "aarch64-pc-windows-gnullvm"},
+	}
+}
+
 func (m *Xs) withCaches(container *dagger.Container, targetSuffix string) *dagger.Container {
 	// Separate caches per target
 	registryCache := dag.CacheVolume("dagger-cargo-registry-" + targetSuffix)
@@ -42,35 +96,32 @@ func (m *Xs) MacosEnv(
 	)
 }
 
-func (m *Xs) MacosBuild(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
-	container := m.MacosEnv(ctx, src).
-		WithExec([]string{"rustup", "update", "stable"}).
-		WithExec([]string{"rustup", "default", "stable"}).
-		WithExec([]string{"rustup", "target", "add", "aarch64-apple-darwin"})
+// macosLibprocRetryScript builds target with cargo, and if the build fails
+// because the libproc crate's bindings source is missing (a known issue on
+// this builder image), copies the missing file into place and retries once.
+func macosLibprocRetryScript(target string) string {
+	return `
+		cargo build --target ` + target + ` --release --color always 2>&1 | tee build.log
 
-	// First build attempt - this will likely fail due to libproc issue
-	container = container.WithExec([]string{"bash", "-c", `
-		cargo build --target aarch64-apple-darwin --release --color always 2>&1 | tee build.log
-		
 		# Check if libproc error occurred
 		if grep -q "osx_libproc_bindings.rs.*No such file" build.log; then
 			echo "Detected libproc issue, applying fix..."
-			
+
 			# Find the libproc source file - try both possible paths
 			SOURCE_FILE=$(find /root/.cargo/registry/src/index.crates.io-* -name "libproc-*" -type d | head -1)/docs_rs/osx_libproc_bindings.rs
 			if [ ! -f "$SOURCE_FILE" ]; then
 				SOURCE_FILE=$(find /root/.cargo/registry/src/index.crates.io-* -name "libproc-*" -type d | head -1)/src/osx_libproc_bindings.rs
 			fi
-			
+
 			# Find the destination directory
-			DEST_DIR=$(find target/aarch64-apple-darwin/release/build/ -name "libproc-*" -type d | head -1)/out
-			
+			DEST_DIR=$(find target/` + target + `/release/build/ -name "libproc-*" -type d | head -1)/out
+
 			if [ -f "$SOURCE_FILE" ] && [ -d "$DEST_DIR" ]; then
 				echo "Copying $SOURCE_FILE to $DEST_DIR/"
 				cp "$SOURCE_FILE" "$DEST_DIR/"
-				
+
 				echo "Retrying build..."
-				cargo build --target aarch64-apple-darwin --release --color always
+				cargo build --target ` + target + ` --release --color always
 			else
 				echo "Error: Could not find source file or destination directory"
 				echo "Source: $SOURCE_FILE"
@@ -78,10 +129,20 @@ func (m *Xs) MacosBuild(ctx context.Context, src *dagger.Directory, version stri
 				exit 1
 			fi
 		fi
-		
+
 		# Clean up log file
 		rm -f build.log
-	`})
+	`
+}
+
+func (m *Xs) MacosBuild(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	container := m.MacosEnv(ctx, src).
+		WithExec([]string{"rustup", "update", "stable"}).
+		WithExec([]string{"rustup", "default", "stable"}).
+		WithExec([]string{"rustup", "target", "add", "aarch64-apple-darwin"})
+
+	// First build attempt - this will likely fail due to libproc issue
+	container = container.WithExec([]string{"bash", "-c", macosLibprocRetryScript("aarch64-apple-darwin")})
 
 	// Create tarball structure using provided version
 	container = container.WithExec([]string{"sh", "-c", `
@@ -94,6 +155,76 @@ func (m *Xs) MacosBuild(ctx context.Context, src *dagger.Directory, version stri
 	return container.File("/tmp/cross-stream-" + version + "-macos.tar.gz")
 }
 
+func (m *Xs) MacosAmd64Env(
+	ctx context.Context,
+	src *dagger.Directory) *dagger.Container {
+	return m.withCaches(
+		dag.Container().
+			From("joseluisq/rust-linux-darwin-builder:latest").
+			WithEnvVariable("CC_x86_64_apple_darwin", "x86_64-apple-darwin22.4-clang").
+			WithEnvVariable("CXX_x86_64_apple_darwin", "x86_64-apple-darwin22.4-clang++").
+			WithEnvVariable("AR_x86_64_apple_darwin", "x86_64-apple-darwin22.4-ar").
+			WithEnvVariable("CFLAGS_x86_64_apple_darwin", "-fuse-ld=/usr/local/osxcross/target/bin/x86_64-apple-darwin22.4-ld").
+			WithMountedDirectory("/app", src).
+			WithWorkdir("/app"),
+		"darwin-amd64",
+	)
+}
+
+func (m *Xs) MacosAmd64Build(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	container := m.MacosAmd64Env(ctx, src).
+		WithExec([]string{"rustup", "update", "stable"}).
+		WithExec([]string{"rustup", "default", "stable"}).
+		WithExec([]string{"rustup", "target", "add", "x86_64-apple-darwin"}).
+		WithExec([]string{"cargo", "build", "--target", "x86_64-apple-darwin", "--release", "--color", "always"})
+
+	// Create tarball structure using provided version
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp target/x86_64-apple-darwin/release/xs /tmp/cross-stream-` + version + `/
+		cd /tmp
+		tar -czf cross-stream-` + version + `-macos-amd64.tar.gz cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-macos-amd64.tar.gz")
+}
+
+// MacosUniversalBuild builds both macOS architectures and glues them into a
+// single fat Mach-O with osxcross's lipo, so Intel and Apple Silicon users
+// can download one artifact.
+func (m *Xs) MacosUniversalBuild(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	arm64 := m.MacosEnv(ctx, src).
+		WithExec([]string{"rustup", "update", "stable"}).
+		WithExec([]string{"rustup", "default", "stable"}).
+		WithExec([]string{"rustup", "target", "add", "aarch64-apple-darwin"}).
+		WithExec([]string{"bash", "-c", macosLibprocRetryScript("aarch64-apple-darwin")}).
+		File("target/aarch64-apple-darwin/release/xs")
+
+	amd64 := m.MacosAmd64Env(ctx, src).
+		WithExec([]string{"rustup", "update", "stable"}).
+		WithExec([]string{"rustup", "default", "stable"}).
+		WithExec([]string{"rustup", "target", "add", "x86_64-apple-darwin"}).
+		WithExec([]string{"cargo", "build", "--target", "x86_64-apple-darwin", "--release", "--color", "always"}).
+		File("target/x86_64-apple-darwin/release/xs")
+
+	container := dag.Container().
+		From("joseluisq/rust-linux-darwin-builder:latest").
+		WithFile("/tmp/xs-aarch64-apple-darwin", arm64).
+		WithFile("/tmp/xs-x86_64-apple-darwin", amd64).
+		WithWorkdir("/tmp").
+		WithExec([]string{"lipo", "-create", "-output", "xs", "xs-aarch64-apple-darwin", "xs-x86_64-apple-darwin"}).
+		WithExec([]string{"lipo", "-info", "xs"})
+
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp /tmp/xs /tmp/cross-stream-` + version + `/
+		cd /tmp
+		tar -czf cross-stream-` + version + `-macos-universal.tar.gz cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-macos-universal.tar.gz")
+}
+
 func (m *Xs) LinuxArm64Env(
 	ctx context.Context,
 	src *dagger.Directory) *dagger.Container {
@@ -147,3 +278,349 @@ func (m *Xs) LinuxAmd64Build(ctx context.Context, src *dagger.Directory, version
 
 	return container.File("/tmp/cross-stream-" + version + "-linux-amd64.tar.gz")
 }
+
+func (m *Xs) LinuxAmd64GnuEnv(
+	ctx context.Context,
+	src *dagger.Directory) *dagger.Container {
+	return m.withCaches(
+		dag.Container().
+			From("quay.io/pypa/manylinux2014_x86_64").
+			WithExec([]string{"curl", "--proto", "=https", "--tlsv1.2", "-sSf", "https://sh.rustup.rs", "-o", "/tmp/rustup-init.sh"}).
+			WithExec([]string{"sh", "/tmp/rustup-init.sh", "-y", "--default-toolchain", "stable"}).
+			WithEnvVariable("PATH", "/root/.cargo/bin:$PATH", dagger.ContainerWithEnvVariableOpts{Expand: true}).
+			WithExec([]string{"rustup", "target", "add", "x86_64-unknown-linux-gnu"}).
+			WithMountedDirectory("/app", src).
+			WithWorkdir("/app"),
+		"linux-amd64-gnu",
+	)
+}
+
+func (m *Xs) LinuxAmd64GnuBuild(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	container := m.LinuxAmd64GnuEnv(ctx, src).
+		WithExec([]string{"cargo", "build", "--release", "--target", "x86_64-unknown-linux-gnu"})
+
+	// Create tarball structure using provided version
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp target/x86_64-unknown-linux-gnu/release/xs /tmp/cross-stream-` + version + `/
+		cd /tmp
+		tar -czf cross-stream-` + version + `-linux-amd64-gnu.tar.gz cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-linux-amd64-gnu.tar.gz")
+}
+
+func (m *Xs) LinuxArm64GnuEnv(
+	ctx context.Context,
+	src *dagger.Directory) *dagger.Container {
+	return m.withCaches(
+		dag.Container(dagger.ContainerOpts{Platform: "linux/arm64"}).
+			From("quay.io/pypa/manylinux2014_aarch64").
+			WithExec([]string{"curl", "--proto", "=https", "--tlsv1.2", "-sSf", "https://sh.rustup.rs", "-o", "/tmp/rustup-init.sh"}).
+			WithExec([]string{"sh", "/tmp/rustup-init.sh", "-y", "--default-toolchain", "stable"}).
+			WithEnvVariable("PATH", "/root/.cargo/bin:$PATH", dagger.ContainerWithEnvVariableOpts{Expand: true}).
+			WithExec([]string{"rustup", "target", "add", "aarch64-unknown-linux-gnu"}).
+			WithMountedDirectory("/app", src).
+			WithWorkdir("/app"),
+		"linux-arm64-gnu",
+	)
+}
+
+func (m *Xs) LinuxArm64GnuBuild(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	container := m.LinuxArm64GnuEnv(ctx, src).
+		WithExec([]string{"cargo", "build", "--release", "--target", "aarch64-unknown-linux-gnu"})
+
+	// Create tarball structure using provided version
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp target/aarch64-unknown-linux-gnu/release/xs /tmp/cross-stream-` + version + `/
+		cd /tmp
+		tar -czf cross-stream-` + version + `-linux-arm64-gnu.tar.gz cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-linux-arm64-gnu.tar.gz")
+}
+
+func (m *Xs) WindowsAmd64Env(
+	ctx context.Context,
+	src *dagger.Directory) *dagger.Container {
+	return m.withCaches(
+		dag.Container().
+			From("rust:latest").
+			WithExec([]string{"apt-get", "update"}).
+			WithExec([]string{"apt-get", "install", "-y", "mingw-w64", "zip"}).
+			WithExec([]string{"rustup", "target", "add", "x86_64-pc-windows-gnu"}).
+			WithEnvVariable("CARGO_TARGET_X86_64_PC_WINDOWS_GNU_LINKER", "x86_64-w64-mingw32-gcc").
+			WithMountedDirectory("/app", src).
+			WithWorkdir("/app"),
+		"windows-amd64",
+	)
+}
+
+func (m *Xs) WindowsAmd64Build(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	container := m.WindowsAmd64Env(ctx, src).
+		WithExec([]string{"cargo", "build", "--release", "--target", "x86_64-pc-windows-gnu"})
+
+	// Create zip structure using provided version
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp target/x86_64-pc-windows-gnu/release/xs.exe /tmp/cross-stream-` + version + `/
+		cd /tmp
+		zip -r cross-stream-` + version + `-windows-amd64.zip cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-windows-amd64.zip")
+}
+
+// llvm-mingw version pinned for the aarch64-pc-windows-gnullvm target: unlike
+// x86_64/i686, Debian's mingw-w64 apt package ships no aarch64-w64-mingw32-*
+// binaries at all, so that triple needs the LLVM-based llvm-mingw toolchain
+// (clang+lld) instead of GNU mingw-w64.
+const llvmMingwVersion = "20240919"
+
+func (m *Xs) WindowsArm64Env(
+	ctx context.Context,
+	src *dagger.Directory) *dagger.Container {
+	return m.withCaches(
+		dag.Container().
+			From("rust:latest").
+			WithExec([]string{"apt-get", "update"}).
+			WithExec([]string{"apt-get", "install", "-y", "curl", "zip"}).
+			WithExec([]string{"sh", "-c", `
+				curl -sSL -o /tmp/llvm-mingw.tar.xz "https://github.com/mstorsjo/llvm-mingw/releases/download/` + llvmMingwVersion + `/llvm-mingw-` + llvmMingwVersion + `-ucrt-ubuntu-20.04-x86_64.tar.xz"
+				tar -xJf /tmp/llvm-mingw.tar.xz -C /opt
+				mv /opt/llvm-mingw-` + llvmMingwVersion + `-ucrt-ubuntu-20.04-x86_64 /opt/llvm-mingw
+				rm -f /tmp/llvm-mingw.tar.xz
+			`}).
+			WithEnvVariable("PATH", "/opt/llvm-mingw/bin:$PATH", dagger.ContainerWithEnvVariableOpts{Expand: true}).
+			WithExec([]string{"rustup", "target", "add", "aarch64-pc-windows-gnullvm"}).
+			WithEnvVariable("CARGO_TARGET_AARCH64_PC_WINDOWS_GNULLVM_LINKER", "aarch64-w64-mingw32-clang").
+			WithEnvVariable("CC_aarch64_pc_windows_gnullvm", "aarch64-w64-mingw32-clang").
+			WithMountedDirectory("/app", src).
+			WithWorkdir("/app"),
+		"windows-arm64",
+	)
+}
+
+func (m *Xs) WindowsArm64Build(ctx context.Context, src *dagger.Directory, version string) *dagger.File {
+	container := m.WindowsArm64Env(ctx, src).
+		WithExec([]string{"cargo", "build", "--release", "--target", "aarch64-pc-windows-gnullvm"})
+
+	// Create zip structure using provided version
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp target/aarch64-pc-windows-gnullvm/release/xs.exe /tmp/cross-stream-` + version + `/
+		cd /tmp
+		zip -r cross-stream-` + version + `-windows-arm64.zip cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-windows-arm64.zip")
+}
+
+// BuildAll fans out the requested targets concurrently and assembles their
+// archives (tarballs, or zips for Windows), plus a manifest.json describing each artifact (target triple,
+// filename, checksums, size, version, and build timing), into a single
+// Directory suitable for a release upload step.
+func (m *Xs) BuildAll(
+	ctx context.Context,
+	src *dagger.Directory,
+	version string,
+	targets []string,
+) (*dagger.Directory, error) {
+	available := m.buildTargets()
+
+	// Validate every requested target before dispatching any build, so a
+	// bad name later in the slice can't leave an earlier, already-started
+	// build running ungoverned (no errgroup goroutine is ever left unawaited).
+	resolved := make([]buildTarget, len(targets))
+	for i, target := range targets {
+		bt, ok := available[target]
+		if !ok {
+			return nil, fmt.Errorf("unknown build target %q", target)
+		}
+		resolved[i] = bt
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	var mu sync.Mutex
+	artifacts := make([]artifactMeta, 0, len(targets))
+	dir := dag.Directory()
+
+	for i, target := range targets {
+		target := target
+		bt := resolved[i]
+		g.Go(func() error {
+			start := time.Now()
+			file := bt.build(ctx, src, version)
+
+			filename, err := file.Name(ctx)
+			if err != nil {
+				return fmt.Errorf("target %s: %w", target, err)
+			}
+			contents, err := file.Contents(ctx)
+			if err != nil {
+				return fmt.Errorf("target %s: %w", target, err)
+			}
+			finish := time.Now()
+
+			sum256 := sha256.Sum256([]byte(contents))
+			sum512 := sha512.Sum512([]byte(contents))
+
+			meta := artifactMeta{
+				Name:       target,
+				Target:     bt.triple,
+				Filename:   filename,
+				SHA256:     hex.EncodeToString(sum256[:]),
+				SHA512:     hex.EncodeToString(sum512[:]),
+				Size:       len(contents),
+				Version:    version,
+				StartedAt:  start.UTC().Format(time.RFC3339),
+				FinishedAt: finish.UTC().Format(time.RFC3339),
+				DurationMs: finish.Sub(start).Milliseconds(),
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			artifacts = append(artifacts, meta)
+			dir = dir.WithFile(filename, file)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	manifest, err := json.MarshalIndent(artifacts, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	return dir.WithNewFile("manifest.json", string(manifest)), nil
+}
+
+// PublishImage builds the Linux amd64 and arm64 binaries, packages each into
+// a minimal scratch-based container, and publishes a single multi-arch
+// manifest list to registryRef (a repository without a tag) twice: tagged
+// ":<version>" and ":latest". It returns the reference of the ":latest" push.
+func (m *Xs) PublishImage(
+	ctx context.Context,
+	src *dagger.Directory,
+	version string,
+	registryRef string,
+	username string,
+	password *dagger.Secret,
+) (string, error) {
+	amd64 := m.LinuxAmd64Env(ctx, src).
+		WithExec([]string{"cargo", "build", "--release", "--target", "x86_64-unknown-linux-musl"}).
+		File("target/x86_64-unknown-linux-musl/release/xs")
+
+	arm64 := m.LinuxArm64Env(ctx, src).
+		WithExec([]string{"cargo", "build", "--release", "--target", "aarch64-unknown-linux-musl"}).
+		File("target/aarch64-unknown-linux-musl/release/xs")
+
+	created := time.Now().UTC().Format(time.RFC3339)
+	registryHost := registryRef
+	if i := strings.Index(registryRef, "/"); i != -1 {
+		registryHost = registryRef[:i]
+	}
+
+	image := func(platform dagger.Platform, binary *dagger.File) *dagger.Container {
+		return dag.Container(dagger.ContainerOpts{Platform: platform}).
+			From("scratch").
+			WithFile("/usr/local/bin/xs", binary).
+			WithEntrypoint([]string{"/usr/local/bin/xs"}).
+			WithLabel("org.opencontainers.image.source", "https://github.com/cablehead/xs").
+			WithLabel("org.opencontainers.image.revision", version).
+			WithLabel("org.opencontainers.image.version", version).
+			WithLabel("org.opencontainers.image.created", created).
+			WithRegistryAuth(registryHost, username, password)
+	}
+
+	variants := []*dagger.Container{
+		image("linux/amd64", amd64),
+		image("linux/arm64", arm64),
+	}
+
+	ref := registryRef + ":" + version
+	if _, err := variants[0].Publish(ctx, ref, dagger.ContainerPublishOpts{
+		PlatformVariants: variants,
+	}); err != nil {
+		return "", fmt.Errorf("publish %s: %w", ref, err)
+	}
+
+	latest := registryRef + ":latest"
+	digest, err := variants[0].Publish(ctx, latest, dagger.ContainerPublishOpts{
+		PlatformVariants: variants,
+	})
+	if err != nil {
+		return "", fmt.Errorf("publish %s: %w", latest, err)
+	}
+
+	return digest, nil
+}
+
+// androidClangPrefix maps a Rust Android target triple to the prefix used by
+// the NDK's per-API-level clang wrappers (armv7 is the one oddball: its
+// clang binary is prefixed "armv7a", not the triple's "armv7").
+var androidClangPrefix = map[string]string{
+	"aarch64-linux-android":   "aarch64-linux-android",
+	"armv7-linux-androideabi": "armv7a-linux-androideabi",
+	"x86_64-linux-android":    "x86_64-linux-android",
+}
+
+// AndroidBuild cross-compiles xs for an Android target triple using a
+// downloaded NDK, caching the NDK itself in a dedicated CacheVolume so
+// repeated builds don't re-fetch it.
+func (m *Xs) AndroidBuild(
+	ctx context.Context,
+	src *dagger.Directory,
+	version string,
+	// Rust target triple, e.g. "aarch64-linux-android", "armv7-linux-androideabi", "x86_64-linux-android"
+	arch string,
+	// +default="r26"
+	ndkVersion string,
+	// +default="24"
+	apiLevel string,
+) (*dagger.File, error) {
+	clangPrefix, ok := androidClangPrefix[arch]
+	if !ok {
+		return nil, fmt.Errorf("unsupported android arch %q", arch)
+	}
+
+	ndkCache := dag.CacheVolume("android-ndk-" + ndkVersion)
+	ndkRoot := "/opt/android-ndk-" + ndkVersion
+	toolchainBin := ndkRoot + "/toolchains/llvm/prebuilt/linux-x86_64/bin"
+	clang := toolchainBin + "/" + clangPrefix + apiLevel + "-clang"
+
+	container := m.withCaches(
+		dag.Container().
+			From("rust:latest").
+			WithMountedCache("/opt", ndkCache).
+			WithExec([]string{"sh", "-c", `
+				if [ ! -d "` + ndkRoot + `" ]; then
+					curl -sSL -o /tmp/android-ndk.zip "https://dl.google.com/android/repo/android-ndk-` + ndkVersion + `-linux.zip"
+					unzip -q /tmp/android-ndk.zip -d /opt
+					rm -f /tmp/android-ndk.zip
+				fi
+			`}).
+			WithExec([]string{"rustup", "target", "add", arch}).
+			WithEnvVariable("CARGO_TARGET_"+strings.ToUpper(strings.ReplaceAll(arch, "-", "_"))+"_LINKER", clang).
+			WithEnvVariable("CC_"+strings.ReplaceAll(arch, "-", "_"), clang).
+			WithMountedDirectory("/app", src).
+			WithWorkdir("/app"),
+		"android-"+arch,
+	)
+
+	container = container.WithExec([]string{"cargo", "build", "--release", "--target", arch})
+
+	// Create tarball structure using provided version
+	container = container.WithExec([]string{"sh", "-c", `
+		mkdir -p /tmp/cross-stream-` + version + `
+		cp target/` + arch + `/release/xs /tmp/cross-stream-` + version + `/
+		cd /tmp
+		tar -czf cross-stream-` + version + `-android-` + arch + `.tar.gz cross-stream-` + version + `
+	`})
+
+	return container.File("/tmp/cross-stream-" + version + "-android-" + arch + ".tar.gz"), nil
+}